@@ -37,10 +37,12 @@ type filterInfo struct {
 }
 
 type worldInfo struct {
-	Name         string
-	InfoTypeName string
-	Components   []componentInfo
-	Filters      []filterInfo
+	Name          string
+	InfoTypeName  string
+	Components    []componentInfo
+	Events        []componentInfo
+	Filters       []filterInfo
+	ArchetypeCode string
 }
 
 func newComponentInfo(typeName string) componentInfo {
@@ -83,10 +85,10 @@ func main() {
 		w := &worlds[i]
 		scanWorldInfo(f, w)
 		validateFilters(w)
+		w.ArchetypeCode = generateArchetypeCode(w)
 	}
 
-	var buf bytes.Buffer
-	if err := packageTemplate.Execute(&buf, struct {
+	templateData := struct {
 		Package string
 		Imports []string
 		Worlds  []worldInfo
@@ -94,16 +96,24 @@ func main() {
 		Package: inPackage,
 		Imports: imports,
 		Worlds:  worlds,
-	}); err != nil {
+	}
+
+	base := inFileName[:len(inFileName)-len(filepath.Ext(inFileName))]
+	dir := filepath.Dir(inFileName)
+	writeGenerated(packageTemplate, templateData, filepath.Join(dir, fmt.Sprintf("%s-gen.go", base)))
+	writeGenerated(fastFilterTemplate, templateData, filepath.Join(dir, fmt.Sprintf("%s-gen-fast.go", base)))
+	writeGenerated(legacyFilterTemplate, templateData, filepath.Join(dir, fmt.Sprintf("%s-gen-legacy.go", base)))
+}
+
+func writeGenerated(tpl *template.Template, data interface{}, outFileName string) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
 		panic(err)
 	}
 	formattedCode, err := format.Source(buf.Bytes())
 	if err != nil {
 		panic(err)
 	}
-	dir := filepath.Dir(inFileName)
-	outFileName := filepath.Join(dir,
-		fmt.Sprintf("%s-gen.go", inFileName[:len(inFileName)-len(filepath.Ext(inFileName))]))
 	w, err := os.Create(outFileName)
 	if err != nil {
 		panic(err)
@@ -150,6 +160,7 @@ func scanWorldInfo(f *ast.File, worldInfo *worldInfo) {
 				if t.Name.Name == worldInfo.InfoTypeName {
 					fmt.Printf("world-info found: %s\n", worldInfo.InfoTypeName)
 					componentsFound := false
+					eventsFound := false
 					for _, method := range t.Type.(*ast.InterfaceType).Methods.List {
 						if len(method.Names) == 0 {
 							continue
@@ -157,6 +168,17 @@ func scanWorldInfo(f *ast.File, worldInfo *worldInfo) {
 						fnName := method.Names[0]
 						fn := method.Type.(*ast.FuncType)
 						if !fnName.IsExported() {
+							if fnName.Name == "events" {
+								if eventsFound {
+									panic(fmt.Sprintf(`only one private "events" func should be present in world "%s"`, worldInfo.Name))
+								}
+								worldInfo.Events = scanEvents(worldInfo, fn)
+								eventsFound = true
+								for _, ei := range worldInfo.Events {
+									fmt.Printf("event: name=%s, type=%s\n", ei.Name, ei.Type)
+								}
+								continue
+							}
 							if componentsFound {
 								panic(fmt.Sprintf(`only one private func should be present in world "%s"`, worldInfo.Name))
 							}
@@ -203,6 +225,31 @@ func scanComponents(w *worldInfo, name string, fn *ast.FuncType) []componentInfo
 	return components
 }
 
+func scanEvents(w *worldInfo, fn *ast.FuncType) []componentInfo {
+	var events []componentInfo
+	if len(fn.Params.List) > 0 {
+		panic(fmt.Sprintf(`private func "events" cant get parameters in world "%s"`, w.Name))
+	}
+	if fn.Results == nil {
+		panic(fmt.Sprintf(`private func "events" should returns events in world "%s"`, w.Name))
+	}
+	for _, par := range fn.Results.List {
+		var typeName string
+		switch par.Type.(type) {
+		case *ast.SelectorExpr:
+			sel := par.Type.(*ast.SelectorExpr)
+			typeName = fmt.Sprintf("%s.%s", sel.X.(*ast.Ident).Name, sel.Sel)
+		case *ast.Ident:
+			typeName = par.Type.(*ast.Ident).Name
+		}
+		if idx := findComponentByType(events, typeName); idx != -1 {
+			panic(fmt.Sprintf(`event "%s" already declared in world "%s"`, typeName, w.Name))
+		}
+		events = append(events, newComponentInfo(typeName))
+	}
+	return events
+}
+
 func scanFilterConstraints(fn *ast.FuncType) filterInfo {
 	filter := filterInfo{}
 	for _, par := range fn.Params.List {
@@ -269,6 +316,11 @@ func validateFilters(w *worldInfo) {
 		cNames = append(cNames, c.Name)
 	}
 	fmt.Printf("components: %v\n", cNames)
+	var eNames []string
+	for _, e := range w.Events {
+		eNames = append(eNames, e.Name)
+	}
+	fmt.Printf("events: %v\n", eNames)
 	for _, f := range w.Filters {
 		fmt.Printf("filter \"%s\": include=%v, exclude=%v\n", f.Name, f.IncludeTypes, f.ExcludeTypes)
 	}
@@ -291,8 +343,7 @@ var packageTemplate = template.Must(template.New("").Funcs(templateFuncs).Parse(
 package {{ .Package }}
 
 import (
-	"sort"
-{{ range $i,$import := .Imports }}	
+{{ range $i,$import := .Imports }}
 	{{$import}}
 {{- end}}
 )
@@ -300,8 +351,7 @@ import (
 {{- $worldName := $world.Name }}
 // New{{$worldName}} returns new instance of {{$worldName}}.
 func New{{$worldName}}(entitiesCount uint32) *{{$worldName}} {
-	return &{{$worldName}}{
-		world: ecs.NewWorld(entitiesCount, []ecs.ComponentPool{
+	world := ecs.NewWorld(entitiesCount, []ecs.ComponentPool{
 {{- range $i,$c := $world.Components }}
 		new{{$c.Name}}Pool(entitiesCount),
 {{- end}}
@@ -309,8 +359,15 @@ func New{{$worldName}}(entitiesCount uint32) *{{$worldName}} {
 {{- range $i,$f := $world.Filters }}
 		*ecs.NewFilter([]uint16{ {{ joinSlice $f.IncludeIndices }} }, []uint16{ {{ joinSlice $f.ExcludeIndices }} }, 512),
 {{- end}}
-		}),
-	}
+		})
+{{- if $world.Events }}
+	world.AddEvents(
+{{- range $i,$e := $world.Events }}
+		ecs.NewEvents[{{$e.Type}}](),
+{{- end}}
+	)
+{{- end}}
+	return &{{$worldName}}{world: world}
 }
 
 // InternalWorld returns internal ecs.World instance.
@@ -351,6 +408,63 @@ func (p *pool{{$c.Name}}) Recycle(idx uint32) {
 	(*p)[idx] = {{$c.Type}}{}
 }
 
+// Get{{$c.Name}} returns exist {{$c.Name}} component on entity or nil.
+func (w {{$worldName}}) Get{{$c.Name}}(entity ecs.Entity) *{{$c.Type}} {
+	if !w.world.Entities[entity].BitMask.Get({{$i}}) {
+		return nil
+	}
+	return &(*w.world.Pools[{{$i}}].(*pool{{$c.Name}}))[entity]
+}
+
+// Get{{$c.Name}}Unsafe returns exist {{$c.Name}} component on entity or nil.
+func (w {{$worldName}}) Get{{$c.Name}}Unsafe(entity ecs.Entity) *{{$c.Type}} {
+	return &(*w.world.Pools[{{$i}}].(*pool{{$c.Name}}))[entity]
+}
+{{- end}}
+{{- range $i,$f := $world.Filters }}
+// {{$f.Name}} returns user filter.
+func (w {{$worldName}}) {{$f.Name}}() *ecs.Filter {
+	return w.world.Filter({{$i}})
+}
+{{- end}}
+{{- range $i,$e := $world.Events }}
+// Send{{$e.Name}} writes new {{$e.Name}} event, visible to readers during this and the next Systems.Run() cycle.
+func (w {{$worldName}}) Send{{$e.Name}}(evt {{$e.Type}}) {
+	w.world.Events[{{$i}}].(*ecs.Events[{{$e.Type}}]).Send(evt)
+}
+
+// New{{$e.Name}}Reader returns new reader cursor for {{$e.Name}} events.
+func (w {{$worldName}}) New{{$e.Name}}Reader() *ecs.EventReader[{{$e.Type}}] {
+	return w.world.Events[{{$i}}].(*ecs.Events[{{$e.Type}}]).NewReader()
+}
+
+// Read{{$e.Name}} returns all unread {{$e.Name}} events for reader and advances its cursor.
+func (w {{$worldName}}) Read{{$e.Name}}(reader *ecs.EventReader[{{$e.Type}}]) []{{$e.Type}} {
+	return reader.Read()
+}
+{{- end}}
+{{- end}}
+`))
+
+// legacyFilterTemplate renders SetXxx/DelXxx with the original binary-search
+// over Mask / UpdateFilters scan, kept behind the "ecs_legacy_filters" build
+// tag so the archetype-graph fast path (fastFilterTemplate) can be checked
+// against it for correctness.
+var legacyFilterTemplate = template.Must(template.New("").Funcs(templateFuncs).Parse(
+	`//go:build ecs_legacy_filters
+
+// Code generated by "go generate", DO NOT EDIT.
+package {{ .Package }}
+
+import (
+	"sort"
+{{ range $i,$import := .Imports }}
+	{{$import}}
+{{- end}}
+)
+{{ range $worldIdx,$world := .Worlds }}
+{{- $worldName := $world.Name }}
+{{- range $i,$c := $world.Components }}
 // Set{{$c.Name}} adds or returns exist {{$c.Name}} component on entity.
 func (w {{$worldName}}) Set{{$c.Name}}(entity ecs.Entity) *{{$c.Type}} {
 	entityData := &w.world.Entities[entity]
@@ -361,24 +475,12 @@ func (w {{$worldName}}) Set{{$c.Name}}(entity ecs.Entity) *{{$c.Type}} {
 		entityData.Mask = append(entityData.Mask, 0)
 		copy(entityData.Mask[maskIdx+1:], entityData.Mask[maskIdx:])
 		entityData.Mask[maskIdx] = {{$i}}
+		entityData.RoaringMask.Add({{$i}})
 		w.world.UpdateFilters(entity, {{$i}}, true)
 	}
 	return &(*pool)[entity]
 }
 
-// Get{{$c.Name}} returns exist {{$c.Name}} component on entity or nil.
-func (w {{$worldName}}) Get{{$c.Name}}(entity ecs.Entity) *{{$c.Type}} {
-	if !w.world.Entities[entity].BitMask.Get({{$i}}) {
-		return nil
-	}
-	return &(*w.world.Pools[{{$i}}].(*pool{{$c.Name}}))[entity]
-}
-
-// Get{{$c.Name}}Unsafe returns exist {{$c.Name}} component on entity or nil.
-func (w {{$worldName}}) Get{{$c.Name}}Unsafe(entity ecs.Entity) *{{$c.Type}} {
-	return &(*w.world.Pools[{{$i}}].(*pool{{$c.Name}}))[entity]
-}
-
 // Del{{$c.Name}} removes {{$c.Name}} component or do nothing.
 // If entity is empty after removing - it will be destroyed automatically.
 func (w {{$worldName}}) Del{{$c.Name}}(entity ecs.Entity) {
@@ -392,17 +494,228 @@ func (w {{$worldName}}) Del{{$c.Name}}(entity ecs.Entity) {
 			copy(entityData.Mask[maskIdx:], entityData.Mask[maskIdx+1:])
 			entityData.Mask = entityData.Mask[:maskLen-1]
 			entityData.BitMask.Unset({{$i}})
+			entityData.RoaringMask.Remove({{$i}})
 		} else {
 			w.DelEntity(entity)
 		}
 	}
 }
 {{- end}}
-{{- range $i,$f := $world.Filters }}
-// {{$f.Name}} returns user filter.
-func (w {{$worldName}}) {{$f.Name}}() *ecs.Filter {
-	return w.world.Filter({{$i}})
-}
 {{- end}}
+`))
+
+// fastFilterTemplate renders SetXxx/DelXxx on top of the generated
+// per-world archetype graph (ArchetypeCode), active unless the
+// "ecs_legacy_filters" build tag selects legacyFilterTemplate instead.
+var fastFilterTemplate = template.Must(template.New("").Funcs(templateFuncs).Parse(
+	`//go:build !ecs_legacy_filters
+
+// Code generated by "go generate", DO NOT EDIT.
+package {{ .Package }}
+
+import (
+	"sync"
+{{ range $i,$import := .Imports }}
+	{{$import}}
+{{- end}}
+)
+{{ range $worldIdx,$world := .Worlds }}
+{{ $world.ArchetypeCode }}
 {{- end}}
 `))
+
+// maskLiteral renders a Go array literal of chunkCount uint64 words with the
+// bits for the given (string) component indices set, used to bake per-filter
+// include/exclude masks at generation time.
+func maskLiteral(indices []string, chunkCount int) string {
+	words := make([]uint64, chunkCount)
+	for _, s := range indices {
+		idx, err := strconv.Atoi(s)
+		if err != nil {
+			panic(err)
+		}
+		words[idx/64] |= 1 << uint(idx%64)
+	}
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, word := range words {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "0x%x", word)
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// generateArchetypeCode renders the lazily-materialized archetype graph and
+// the fast SetXxx/DelXxx methods for world w: they hash the entity's current
+// BitMask into a generated map[archetypeKey]*archetypeNode, follow a
+// precomputed edge, and apply the exact filter deltas (plus exact new sorted
+// Mask contents) cached on it - no sort.Search, no scanning
+// filtersByInclude/filtersByExclude. The graph is package-level state shared
+// by every instance of the generated world type, so all access to it goes
+// through a mutex (archetypeNodes{{Name}}Mu).
+func generateArchetypeCode(w *worldInfo) string {
+	componentsCount := len(w.Components)
+	if componentsCount == 0 {
+		return ""
+	}
+	chunkCount := (componentsCount-1)/64 + 1
+	name := w.Name
+
+	componentFilterIncludes := make([][]int, componentsCount)
+	componentFilterExcludes := make([][]int, componentsCount)
+	for fIdx, flt := range w.Filters {
+		for _, s := range flt.IncludeIndices {
+			idx, _ := strconv.Atoi(s)
+			componentFilterIncludes[idx] = append(componentFilterIncludes[idx], fIdx)
+		}
+		for _, s := range flt.ExcludeIndices {
+			idx, _ := strconv.Atoi(s)
+			componentFilterExcludes[idx] = append(componentFilterExcludes[idx], fIdx)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// archetypeKey%s is a fixed-size snapshot of a BitMask sized for\n", name)
+	fmt.Fprintf(&sb, "// %s's %d component types, used as the lookup key into the generated\n", name, componentsCount)
+	fmt.Fprintf(&sb, "// archetype graph below.\n")
+	fmt.Fprintf(&sb, "type archetypeKey%s [%d]uint64\n\n", name, chunkCount)
+
+	fmt.Fprintf(&sb, "func archetypeKeyOf%s(bitMask ecs.BitSet) archetypeKey%s {\n", name, name)
+	fmt.Fprintf(&sb, "\tvar key archetypeKey%s\n", name)
+	fmt.Fprintf(&sb, "\tfor i, chunk := range bitMask {\n\t\tkey[i] = uint64(chunk)\n\t}\n")
+	fmt.Fprintf(&sb, "\treturn key\n}\n\n")
+
+	if len(w.Filters) > 0 {
+		fmt.Fprintf(&sb, "var archetypeFilterInclude%s = [%d]archetypeKey%s{\n", name, len(w.Filters), name)
+		for _, flt := range w.Filters {
+			fmt.Fprintf(&sb, "\t%s,\n", maskLiteral(flt.IncludeIndices, chunkCount))
+		}
+		sb.WriteString("}\n\n")
+		fmt.Fprintf(&sb, "var archetypeFilterExclude%s = [%d]archetypeKey%s{\n", name, len(w.Filters), name)
+		for _, flt := range w.Filters {
+			fmt.Fprintf(&sb, "\t%s,\n", maskLiteral(flt.ExcludeIndices, chunkCount))
+		}
+		sb.WriteString("}\n\n")
+
+		fmt.Fprintf(&sb, "func archetypeMatches%s(mask archetypeKey%s, filterIdx int) bool {\n", name, name)
+		fmt.Fprintf(&sb, "\tinc := archetypeFilterInclude%s[filterIdx]\n", name)
+		fmt.Fprintf(&sb, "\texc := archetypeFilterExclude%s[filterIdx]\n", name)
+		fmt.Fprintf(&sb, "\tfor i := range mask {\n")
+		fmt.Fprintf(&sb, "\t\tif mask[i]&inc[i] != inc[i] || mask[i]&exc[i] != 0 {\n")
+		fmt.Fprintf(&sb, "\t\t\treturn false\n\t\t}\n\t}\n\treturn true\n}\n\n")
+	}
+
+	fmt.Fprintf(&sb, "// archetypeNode%s is one vertex of the lazily-built archetype graph:\n", name)
+	fmt.Fprintf(&sb, "// its mask is the exact component set routed through it, and its edges\n")
+	fmt.Fprintf(&sb, "// cache which filters are gained/lost when a single component is\n")
+	fmt.Fprintf(&sb, "// added/removed, plus the resulting sorted Mask contents, so SetXxx/\n")
+	fmt.Fprintf(&sb, "// DelXxx never binary-search Mask or scan filtersByInclude/filtersByExclude again.\n")
+	fmt.Fprintf(&sb, "type archetypeNode%s struct {\n", name)
+	fmt.Fprintf(&sb, "\tmask       archetypeKey%s\n", name)
+	fmt.Fprintf(&sb, "\tsortedMask []uint16\n")
+	fmt.Fprintf(&sb, "\taddEdges   [%d]*archetypeNode%s\n", componentsCount, name)
+	fmt.Fprintf(&sb, "\tdelEdges   [%d]*archetypeNode%s\n", componentsCount, name)
+	fmt.Fprintf(&sb, "\taddGained  [%d][]int\n", componentsCount)
+	fmt.Fprintf(&sb, "\taddLost    [%d][]int\n", componentsCount)
+	fmt.Fprintf(&sb, "\tdelGained  [%d][]int\n", componentsCount)
+	fmt.Fprintf(&sb, "\tdelLost    [%d][]int\n", componentsCount)
+	sb.WriteString("}\n\n")
+
+	fmt.Fprintf(&sb, "// archetypeNodes%s is shared by every %s instance of this generated\n", name, name)
+	fmt.Fprintf(&sb, "// type (the graph only depends on %s's static component/filter layout,\n", name)
+	fmt.Fprintf(&sb, "// not on any one instance's entities), so access is guarded by a mutex -\n")
+	fmt.Fprintf(&sb, "// without it, two %s instances mutated from separate goroutines would\n", name)
+	fmt.Fprintf(&sb, "// race on this map and on each node's lazily-materialized edges.\n")
+	fmt.Fprintf(&sb, "var archetypeNodes%sMu sync.Mutex\n", name)
+	fmt.Fprintf(&sb, "var archetypeNodes%s = map[archetypeKey%s]*archetypeNode%s{}\n\n", name, name, name)
+
+	fmt.Fprintf(&sb, "func archetypeNodeFor%s(mask archetypeKey%s) *archetypeNode%s {\n", name, name, name)
+	fmt.Fprintf(&sb, "\tarchetypeNodes%sMu.Lock()\n", name)
+	fmt.Fprintf(&sb, "\tn := archetypeNodeForLocked%s(mask)\n", name)
+	fmt.Fprintf(&sb, "\tarchetypeNodes%sMu.Unlock()\n", name)
+	fmt.Fprintf(&sb, "\treturn n\n}\n\n")
+
+	fmt.Fprintf(&sb, "// archetypeNodeForLocked%s assumes archetypeNodes%sMu is already held.\n", name, name)
+	fmt.Fprintf(&sb, "func archetypeNodeForLocked%s(mask archetypeKey%s) *archetypeNode%s {\n", name, name, name)
+	fmt.Fprintf(&sb, "\tif n, ok := archetypeNodes%s[mask]; ok {\n\t\treturn n\n\t}\n", name)
+	fmt.Fprintf(&sb, "\tn := &archetypeNode%s{mask: mask}\n", name)
+	fmt.Fprintf(&sb, "\tfor id := 0; id < %d; id++ {\n", componentsCount)
+	fmt.Fprintf(&sb, "\t\tif mask[id/64]&(1<<uint(id%%64)) != 0 {\n")
+	fmt.Fprintf(&sb, "\t\t\tn.sortedMask = append(n.sortedMask, uint16(id))\n\t\t}\n\t}\n")
+	fmt.Fprintf(&sb, "\tarchetypeNodes%s[mask] = n\n\treturn n\n}\n\n", name)
+
+	fmt.Fprintf(&sb, "func archetypeAdd%s(node *archetypeNode%s, typeID uint16) *archetypeNode%s {\n", name, name, name)
+	fmt.Fprintf(&sb, "\tarchetypeNodes%sMu.Lock()\n\tdefer archetypeNodes%sMu.Unlock()\n", name, name)
+	fmt.Fprintf(&sb, "\tif child := node.addEdges[typeID]; child != nil {\n\t\treturn child\n\t}\n")
+	fmt.Fprintf(&sb, "\tchildMask := node.mask\n\tchildMask[typeID/64] |= 1 << (typeID %% 64)\n")
+	fmt.Fprintf(&sb, "\tchild := archetypeNodeForLocked%s(childMask)\n", name)
+	fmt.Fprintf(&sb, "\tvar gained, lost []int\n")
+	fmt.Fprintf(&sb, "\tswitch typeID {\n")
+	for ci := 0; ci < componentsCount; ci++ {
+		fmt.Fprintf(&sb, "\tcase %d:\n", ci)
+		for _, fi := range componentFilterIncludes[ci] {
+			fmt.Fprintf(&sb, "\t\tif archetypeMatches%s(childMask, %d) {\n\t\t\tgained = append(gained, %d)\n\t\t}\n", name, fi, fi)
+		}
+		for _, fi := range componentFilterExcludes[ci] {
+			fmt.Fprintf(&sb, "\t\tif archetypeMatches%s(node.mask, %d) {\n\t\t\tlost = append(lost, %d)\n\t\t}\n", name, fi, fi)
+		}
+	}
+	sb.WriteString("\t}\n")
+	fmt.Fprintf(&sb, "\tnode.addEdges[typeID] = child\n\tnode.addGained[typeID] = gained\n\tnode.addLost[typeID] = lost\n\treturn child\n}\n\n")
+
+	fmt.Fprintf(&sb, "func archetypeDel%s(node *archetypeNode%s, typeID uint16) *archetypeNode%s {\n", name, name, name)
+	fmt.Fprintf(&sb, "\tarchetypeNodes%sMu.Lock()\n\tdefer archetypeNodes%sMu.Unlock()\n", name, name)
+	fmt.Fprintf(&sb, "\tif child := node.delEdges[typeID]; child != nil {\n\t\treturn child\n\t}\n")
+	fmt.Fprintf(&sb, "\tchildMask := node.mask\n\tchildMask[typeID/64] &^= 1 << (typeID %% 64)\n")
+	fmt.Fprintf(&sb, "\tchild := archetypeNodeForLocked%s(childMask)\n", name)
+	fmt.Fprintf(&sb, "\tvar gained, lost []int\n")
+	fmt.Fprintf(&sb, "\tswitch typeID {\n")
+	for ci := 0; ci < componentsCount; ci++ {
+		fmt.Fprintf(&sb, "\tcase %d:\n", ci)
+		for _, fi := range componentFilterExcludes[ci] {
+			fmt.Fprintf(&sb, "\t\tif archetypeMatches%s(childMask, %d) {\n\t\t\tgained = append(gained, %d)\n\t\t}\n", name, fi, fi)
+		}
+		for _, fi := range componentFilterIncludes[ci] {
+			fmt.Fprintf(&sb, "\t\tif archetypeMatches%s(node.mask, %d) {\n\t\t\tlost = append(lost, %d)\n\t\t}\n", name, fi, fi)
+		}
+	}
+	sb.WriteString("\t}\n")
+	fmt.Fprintf(&sb, "\tnode.delEdges[typeID] = child\n\tnode.delGained[typeID] = gained\n\tnode.delLost[typeID] = lost\n\treturn child\n}\n\n")
+
+	for ci, c := range w.Components {
+		fmt.Fprintf(&sb, "// Set%s adds or returns exist %s component on entity.\n", c.Name, c.Name)
+		fmt.Fprintf(&sb, "func (w %s) Set%s(entity ecs.Entity) *%s {\n", name, c.Name, c.Type)
+		fmt.Fprintf(&sb, "\tentityData := &w.world.Entities[entity]\n")
+		fmt.Fprintf(&sb, "\tpool := w.world.Pools[%d].(*pool%s)\n", ci, c.Name)
+		fmt.Fprintf(&sb, "\tif !entityData.BitMask.Get(%d) {\n", ci)
+		fmt.Fprintf(&sb, "\t\tnode := archetypeNodeFor%s(archetypeKeyOf%s(entityData.BitMask))\n", name, name)
+		fmt.Fprintf(&sb, "\t\tchild := archetypeAdd%s(node, %d)\n", name, ci)
+		fmt.Fprintf(&sb, "\t\tentityData.BitMask.Set(%d)\n", ci)
+		fmt.Fprintf(&sb, "\t\tentityData.Mask = append(entityData.Mask[:0], child.sortedMask...)\n")
+		fmt.Fprintf(&sb, "\t\tentityData.RoaringMask.Add(%d)\n", ci)
+		fmt.Fprintf(&sb, "\t\tfor _, fi := range node.addGained[%d] {\n\t\t\tw.world.AddToFilter(fi, entity)\n\t\t}\n", ci)
+		fmt.Fprintf(&sb, "\t\tfor _, fi := range node.addLost[%d] {\n\t\t\tw.world.RemoveFromFilter(fi, entity)\n\t\t}\n", ci)
+		fmt.Fprintf(&sb, "\t}\n\treturn &(*pool)[entity]\n}\n\n")
+
+		fmt.Fprintf(&sb, "// Del%s removes %s component or do nothing.\n", c.Name, c.Name)
+		fmt.Fprintf(&sb, "// If entity is empty after removing - it will be destroyed automatically.\n")
+		fmt.Fprintf(&sb, "func (w %s) Del%s(entity ecs.Entity) {\n", name, c.Name)
+		fmt.Fprintf(&sb, "\tentityData := &w.world.Entities[entity]\n")
+		fmt.Fprintf(&sb, "\tif entityData.BitMask.Get(%d) {\n", ci)
+		fmt.Fprintf(&sb, "\t\tif len(entityData.Mask) > 1 {\n")
+		fmt.Fprintf(&sb, "\t\t\tnode := archetypeNodeFor%s(archetypeKeyOf%s(entityData.BitMask))\n", name, name)
+		fmt.Fprintf(&sb, "\t\t\tchild := archetypeDel%s(node, %d)\n", name, ci)
+		fmt.Fprintf(&sb, "\t\t\tfor _, fi := range node.delLost[%d] {\n\t\t\t\tw.world.RemoveFromFilter(fi, entity)\n\t\t\t}\n", ci)
+		fmt.Fprintf(&sb, "\t\t\tfor _, fi := range node.delGained[%d] {\n\t\t\t\tw.world.AddToFilter(fi, entity)\n\t\t\t}\n", ci)
+		fmt.Fprintf(&sb, "\t\t\tw.world.Pools[%d].(*pool%s).Recycle(entity)\n", ci, c.Name)
+		fmt.Fprintf(&sb, "\t\t\tentityData.BitMask.Unset(%d)\n", ci)
+		fmt.Fprintf(&sb, "\t\t\tentityData.Mask = append(entityData.Mask[:0], child.sortedMask...)\n")
+		fmt.Fprintf(&sb, "\t\t\tentityData.RoaringMask.Remove(%d)\n", ci)
+		fmt.Fprintf(&sb, "\t\t} else {\n\t\t\tw.DelEntity(entity)\n\t\t}\n\t}\n}\n\n")
+	}
+
+	return sb.String()
+}
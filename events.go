@@ -0,0 +1,189 @@
+// ----------------------------------------------------------------------------
+// The MIT License
+// LecsGO - Entity Component System framework powered by Golang.
+// Url: https://github.com/Leopotam/go-ecs
+// Copyright (c) 2021 Leopotam <leopotam@gmail.com>
+// ----------------------------------------------------------------------------
+
+package ecs
+
+// eventsContainer is implemented by every *Events[T] and lets World
+// swap double-buffered event frames and run debug leak checks without
+// knowing the concrete event type.
+type eventsContainer interface {
+	swap()
+	checkLeaked() bool
+}
+
+// Events - double-buffered container for events of type T, registered
+// on a World alongside its component pools. Unread events survive
+// exactly one full Systems.Run() cycle before being dropped.
+type Events[T any] struct {
+	frames     [2][]T
+	starts     [2]uint64
+	currentIdx int
+	eventCount uint64
+	readers    []*EventReader[T]
+	missed     bool
+}
+
+// NewEvents returns new instance of Events.
+func NewEvents[T any]() *Events[T] {
+	return &Events[T]{}
+}
+
+// Send writes new event, it will be visible to readers during this
+// and the next Systems.Run() cycle.
+func (e *Events[T]) Send(evt T) {
+	e.frames[e.currentIdx] = append(e.frames[e.currentIdx], evt)
+	e.eventCount++
+}
+
+// NewReader returns new reader with cursor positioned at the current
+// event count - it wont see events sent before its creation.
+func (e *Events[T]) NewReader() *EventReader[T] {
+	r := &EventReader[T]{events: e, lastEventCount: e.eventCount}
+	e.readers = append(e.readers, r)
+	return r
+}
+
+// oldestAvailable returns the global id of the oldest event still buffered.
+func (e *Events[T]) oldestAvailable() uint64 {
+	other := e.currentIdx ^ 1
+	if len(e.frames[other]) > 0 {
+		return e.starts[other]
+	}
+	return e.starts[e.currentIdx]
+}
+
+func (e *Events[T]) swap() {
+	oldIdx := e.currentIdx ^ 1
+	if DEBUG && len(e.frames[oldIdx]) > 0 {
+		end := e.starts[oldIdx] + uint64(len(e.frames[oldIdx]))
+		for _, r := range e.readers {
+			if r.lastEventCount < end {
+				e.missed = true
+			}
+		}
+	}
+	e.frames[oldIdx] = e.frames[oldIdx][:0]
+	e.starts[oldIdx] = e.eventCount
+	e.currentIdx = oldIdx
+}
+
+func (e *Events[T]) checkLeaked() bool {
+	if e.missed {
+		e.missed = false
+		return true
+	}
+	return false
+}
+
+// EventWriter - producer handle for events of type T, mirrors
+// EventReader on the consumer side.
+type EventWriter[T any] struct {
+	events *Events[T]
+}
+
+// NewEventWriter returns new instance of EventWriter.
+func NewEventWriter[T any](events *Events[T]) *EventWriter[T] {
+	return &EventWriter[T]{events: events}
+}
+
+// Send writes new event through the wrapped Events container.
+func (w *EventWriter[T]) Send(evt T) {
+	w.events.Send(evt)
+}
+
+// EventReader - per-system read cursor over an Events[T] container.
+// It should be created once (usually in PreInit/Init) and kept around
+// between Run() calls, same as a Filter.
+type EventReader[T any] struct {
+	events         *Events[T]
+	lastEventCount uint64
+}
+
+// Read returns all events unread by this reader and advances its cursor.
+func (r *EventReader[T]) Read() []T {
+	n := r.Len()
+	if n == 0 {
+		return nil
+	}
+	from := r.effectiveCursor()
+	result := make([]T, 0, n)
+	e := r.events
+	other := e.currentIdx ^ 1
+	for _, seq := range [2]int{other, e.currentIdx} {
+		base := e.starts[seq]
+		for i, evt := range e.frames[seq] {
+			if base+uint64(i) >= from {
+				result = append(result, evt)
+			}
+		}
+	}
+	r.lastEventCount = e.eventCount
+	return result
+}
+
+// Len returns count of events not yet seen by this reader, without advancing its cursor.
+func (r *EventReader[T]) Len() int {
+	return int(r.events.eventCount - r.effectiveCursor())
+}
+
+// Count consumes all unread events, advancing the cursor, and returns how many there were.
+func (r *EventReader[T]) Count() int {
+	n := r.Len()
+	r.Skip(n)
+	return n
+}
+
+// Last consumes all unread events and returns only the most recent one, if any.
+func (r *EventReader[T]) Last() (T, bool) {
+	n := r.Len()
+	if n == 0 {
+		var zero T
+		return zero, false
+	}
+	evt := r.peek(n - 1)
+	r.Skip(n)
+	return evt, true
+}
+
+// Skip advances the cursor past the next n unread events without returning them.
+func (r *EventReader[T]) Skip(n int) {
+	if n <= 0 {
+		return
+	}
+	cursor := r.effectiveCursor()
+	remaining := r.events.eventCount - cursor
+	if uint64(n) > remaining {
+		n = int(remaining)
+	}
+	r.lastEventCount = cursor + uint64(n)
+}
+
+// effectiveCursor clamps lastEventCount to the oldest still-buffered event,
+// so a reader that lagged behind a swap resumes from there instead of
+// re-reading ids that no longer exist.
+func (r *EventReader[T]) effectiveCursor() uint64 {
+	c := r.lastEventCount
+	if oldest := r.events.oldestAvailable(); c < oldest {
+		c = oldest
+	}
+	return c
+}
+
+func (r *EventReader[T]) peek(offset int) T {
+	from := r.effectiveCursor() + uint64(offset)
+	e := r.events
+	other := e.currentIdx ^ 1
+	for _, seq := range [2]int{other, e.currentIdx} {
+		base := e.starts[seq]
+		n := len(e.frames[seq])
+		if from >= base && from < base+uint64(n) {
+			return e.frames[seq][from-base]
+		}
+	}
+	var zero T
+	return zero
+}
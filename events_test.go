@@ -0,0 +1,83 @@
+// ----------------------------------------------------------------------------
+// The MIT License
+// LecsGO - Entity Component System framework powered by Golang.
+// Url: https://github.com/Leopotam/go-ecs
+// Copyright (c) 2021 Leopotam <leopotam@gmail.com>
+// ----------------------------------------------------------------------------
+
+package ecs
+
+import "testing"
+
+func TestEventsReaderReadsSentEvents(t *testing.T) {
+	events := NewEvents[int]()
+	reader := events.NewReader()
+	events.Send(1)
+	events.Send(2)
+	got := reader.Read()
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("unexpected events: %v", got)
+	}
+	if n := reader.Len(); n != 0 {
+		t.Fatalf("expected no unread events, got %d", n)
+	}
+}
+
+func TestEventsReaderSurvivesOneSwap(t *testing.T) {
+	events := NewEvents[int]()
+	reader := events.NewReader()
+	events.Send(1)
+	events.swap()
+	got := reader.Read()
+	if len(got) != 1 || got[0] != 1 {
+		t.Fatalf("expected to still see event sent before the swap, got %v", got)
+	}
+}
+
+func TestEventsMissedAfterTwoSwapsWithoutRead(t *testing.T) {
+	if !DEBUG {
+		t.Skip("missed-event detection only runs when DEBUG is enabled")
+	}
+	events := NewEvents[int]()
+	events.NewReader()
+	events.Send(1)
+	events.swap()
+	events.swap()
+	if !events.checkLeaked() {
+		t.Fatalf("expected a reader that never read event 1 to be flagged as having missed it")
+	}
+}
+
+func TestEventsNotMissedWhenReaderKeepsUp(t *testing.T) {
+	if !DEBUG {
+		t.Skip("missed-event detection only runs when DEBUG is enabled")
+	}
+	events := NewEvents[int]()
+	reader := events.NewReader()
+	events.Send(1)
+	reader.Read()
+	events.swap()
+	events.swap()
+	if events.checkLeaked() {
+		t.Fatalf("reader consumed its events, should not be flagged as missed")
+	}
+}
+
+func TestEventReaderSkipAndLast(t *testing.T) {
+	events := NewEvents[int]()
+	reader := events.NewReader()
+	events.Send(1)
+	events.Send(2)
+	events.Send(3)
+	reader.Skip(2)
+	if n := reader.Len(); n != 1 {
+		t.Fatalf("expected 1 unread event after skipping 2 of 3, got %d", n)
+	}
+	last, ok := reader.Last()
+	if !ok || last != 3 {
+		t.Fatalf("expected last unread event to be 3, got %v (ok=%v)", last, ok)
+	}
+	if n := reader.Len(); n != 0 {
+		t.Fatalf("Last should have consumed remaining events, got %d left", n)
+	}
+}
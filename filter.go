@@ -18,6 +18,8 @@ type lockedChange struct {
 type Filter struct {
 	include       []uint16
 	exclude       []uint16
+	includeBitmap *RoaringBitmap
+	excludeBitmap *RoaringBitmap
 	entities      []Entity
 	entitiesMap   map[Entity]uint32
 	lockedChanges []lockedChange
@@ -29,6 +31,8 @@ func NewFilter(include []uint16, exclude []uint16, capacity uint32) *Filter {
 	return &Filter{
 		include:       include,
 		exclude:       exclude,
+		includeBitmap: RoaringBitmapFromIDs(include),
+		excludeBitmap: RoaringBitmapFromIDs(exclude),
 		entities:      make([]Entity, 0, capacity),
 		entitiesMap:   make(map[Entity]uint32, capacity),
 		lockedChanges: make([]lockedChange, 0, capacity),
@@ -112,6 +116,10 @@ func (f *Filter) remove(e Entity) {
 	}
 }
 
+// isCompatible is the original sort.Search-over-Mask compatibility check.
+// World.UpdateFilters no longer calls it - IsCompatibleBitmap replaced it as
+// the real hot path - it is kept only as the baseline the roaring_test.go
+// benchmarks compare IsCompatibleBitmap against.
 func (f *Filter) isCompatible(entityData *EntityData) bool {
 	maskLen := len(entityData.Mask)
 	for _, id := range f.include {
@@ -129,6 +137,18 @@ func (f *Filter) isCompatible(entityData *EntityData) bool {
 	return true
 }
 
+// IsCompatibleBitmap is the RoaringBitmap-based equivalent of isCompatible:
+// entityMask must contain every included component and none of the excluded
+// ones. This is the compatibility check World.UpdateFilters actually uses,
+// against entityData.RoaringMask, which every generated SetXxx/DelXxx keeps
+// in sync with Mask/BitMask.
+func (f *Filter) IsCompatibleBitmap(entityMask *RoaringBitmap) bool {
+	return entityMask.ContainsAll(f.includeBitmap) && !entityMask.Intersects(f.excludeBitmap)
+}
+
+// isCompatibleWithout is the original sort.Search-over-Mask equivalent of
+// IsCompatibleBitmapWithout. World.UpdateFilters no longer calls it, and
+// nothing else in the package does either.
 func (f *Filter) isCompatibleWithout(entityData *EntityData, typeID uint16) bool {
 	maskLen := len(entityData.Mask)
 	for _, id := range f.include {
@@ -151,3 +171,19 @@ func (f *Filter) isCompatibleWithout(entityData *EntityData, typeID uint16) bool
 	}
 	return true
 }
+
+// IsCompatibleBitmapWithout is the RoaringBitmap-based equivalent of
+// isCompatibleWithout: it answers IsCompatibleBitmap as if typeID had
+// already been toggled out of entityMask, without needing entityMask
+// actually mutated yet. World.UpdateFilters uses it for the exclude-list
+// side of a Set/Del, matching entityData.RoaringMask's timing (it still
+// contains typeID at that point, same as entityData.Mask).
+func (f *Filter) IsCompatibleBitmapWithout(entityMask *RoaringBitmap, typeID uint16) bool {
+	if f.includeBitmap.Contains(uint32(typeID)) {
+		return false
+	}
+	if !entityMask.ContainsAll(f.includeBitmap) {
+		return false
+	}
+	return !entityMask.IntersectsExcept(f.excludeBitmap, uint32(typeID))
+}
@@ -0,0 +1,592 @@
+// ----------------------------------------------------------------------------
+// The MIT License
+// LecsGO - Entity Component System framework powered by Golang.
+// Url: https://github.com/Leopotam/go-ecs
+// Copyright (c) 2021 Leopotam <leopotam@gmail.com>
+// ----------------------------------------------------------------------------
+
+package ecs
+
+import "sort"
+
+// containerKind identifies which encoding backs a single 16-bit-range container.
+type containerKind uint8
+
+const (
+	containerArray containerKind = iota
+	containerBitmap
+	containerRun
+)
+
+// roaringBitmapWords is the fixed word count of a bitmap container,
+// covering the full 16-bit low range (65536 bits / 64 bits per word).
+const roaringBitmapWords = 1 << 16 / 64
+
+// arrayToBitmapThreshold is the cardinality above which container.add
+// converts an array container to a bitmap container, matching
+// the break-even point where a sorted uint16 array gets bigger than a
+// fixed 1024-word bitmap.
+const arrayToBitmapThreshold = roaringBitmapWords * 64 / 16
+
+// run is one [start, start+length] inclusive range of set bits.
+type run struct {
+	start  uint16
+	length uint16
+}
+
+// container is a single 16-bit-range shard of a RoaringBitmap, encoded
+// as whichever of array/bitmap/run fits its contents best.
+type container struct {
+	kind   containerKind
+	array  []uint16 // sorted, used when kind == containerArray
+	bitmap []uint64 // len <= roaringBitmapWords (see NewRoaringBitmapForComponents), used when kind == containerBitmap
+	runs   []run    // sorted, non-overlapping, used when kind == containerRun
+}
+
+func newArrayContainer() *container {
+	return &container{kind: containerArray}
+}
+
+func (c *container) cardinality() int {
+	switch c.kind {
+	case containerArray:
+		return len(c.array)
+	case containerBitmap:
+		n := 0
+		for _, w := range c.bitmap {
+			n += popcount(w)
+		}
+		return n
+	default:
+		n := 0
+		for _, r := range c.runs {
+			n += int(r.length) + 1
+		}
+		return n
+	}
+}
+
+func (c *container) contains(lo uint16) bool {
+	switch c.kind {
+	case containerArray:
+		idx := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= lo })
+		return idx < len(c.array) && c.array[idx] == lo
+	case containerBitmap:
+		// a bitmap container sized by NewRoaringBitmapForComponents may be
+		// shorter than roaringBitmapWords, so lo can fall past its end.
+		idx := int(lo) / 64
+		return idx < len(c.bitmap) && c.bitmap[idx]&(1<<(lo%64)) != 0
+	default:
+		idx := sort.Search(len(c.runs), func(i int) bool { return c.runs[i].start+c.runs[i].length >= lo })
+		return idx < len(c.runs) && c.runs[idx].start <= lo
+	}
+}
+
+func (c *container) add(lo uint16) {
+	if c.contains(lo) {
+		return
+	}
+	switch c.kind {
+	case containerArray:
+		idx := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= lo })
+		c.array = append(c.array, 0)
+		copy(c.array[idx+1:], c.array[idx:])
+		c.array[idx] = lo
+		if len(c.array) > arrayToBitmapThreshold {
+			c.toBitmap()
+		}
+	case containerBitmap:
+		c.bitmap[lo/64] |= 1 << (lo % 64)
+	default:
+		c.toBitmap()
+		c.add(lo)
+	}
+}
+
+func (c *container) remove(lo uint16) {
+	switch c.kind {
+	case containerArray:
+		idx := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= lo })
+		if idx < len(c.array) && c.array[idx] == lo {
+			copy(c.array[idx:], c.array[idx+1:])
+			c.array = c.array[:len(c.array)-1]
+		}
+	case containerBitmap:
+		c.bitmap[lo/64] &^= 1 << (lo % 64)
+	default:
+		c.toBitmap()
+		c.remove(lo)
+	}
+}
+
+func (c *container) toBitmap() {
+	if c.kind == containerBitmap {
+		return
+	}
+	bitmap := make([]uint64, roaringBitmapWords)
+	switch c.kind {
+	case containerArray:
+		for _, v := range c.array {
+			bitmap[v/64] |= 1 << (v % 64)
+		}
+	case containerRun:
+		for _, r := range c.runs {
+			for v := uint32(r.start); v <= uint32(r.start)+uint32(r.length); v++ {
+				bitmap[v/64] |= 1 << (v % 64)
+			}
+		}
+	}
+	c.kind = containerBitmap
+	c.bitmap = bitmap
+	c.array = nil
+	c.runs = nil
+}
+
+// toRuns converts the container to its sorted list of set ranges,
+// without mutating it - used by optimize() to decide whether a run
+// encoding would be smaller than the current one.
+func (c *container) toRuns() []run {
+	var runs []run
+	push := func(v uint16) {
+		if n := len(runs); n > 0 && runs[n-1].start+runs[n-1].length+1 == v {
+			runs[n-1].length++
+			return
+		}
+		runs = append(runs, run{start: v, length: 0})
+	}
+	switch c.kind {
+	case containerArray:
+		for _, v := range c.array {
+			push(v)
+		}
+	case containerBitmap:
+		for i, w := range c.bitmap {
+			for w != 0 {
+				bit := trailingZeros64(w)
+				push(uint16(i*64 + bit))
+				w &= w - 1
+			}
+		}
+	default:
+		return c.runs
+	}
+	return runs
+}
+
+// optimize converts the container to whichever encoding takes the least
+// memory for its current contents, comparing the byte size of all three
+// representations: array (2 bytes/value), run-length (4 bytes/run) and
+// fixed-size bitmap.
+func (c *container) optimize() {
+	card := c.cardinality()
+	runs := c.toRuns()
+
+	arrayBytes := card * 2
+	bitmapBytes := roaringBitmapWords * 8
+	runBytes := len(runs) * 4
+
+	switch {
+	case runBytes <= arrayBytes && runBytes <= bitmapBytes:
+		c.kind = containerRun
+		c.runs = runs
+		c.array = nil
+		c.bitmap = nil
+	case arrayBytes <= bitmapBytes:
+		arr := make([]uint16, 0, card)
+		for _, r := range runs {
+			for v := uint32(r.start); v <= uint32(r.start)+uint32(r.length); v++ {
+				arr = append(arr, uint16(v))
+			}
+		}
+		c.kind = containerArray
+		c.array = arr
+		c.bitmap = nil
+		c.runs = nil
+	default:
+		c.toBitmap()
+	}
+}
+
+func (c *container) and(o *container) *container {
+	res := newArrayContainer()
+	c.forEach(func(v uint16) {
+		if o.contains(v) {
+			res.add(v)
+		}
+	})
+	return res
+}
+
+func (c *container) or(o *container) *container {
+	res := newArrayContainer()
+	c.forEach(func(v uint16) { res.add(v) })
+	o.forEach(func(v uint16) { res.add(v) })
+	return res
+}
+
+func (c *container) andNot(o *container) *container {
+	res := newArrayContainer()
+	c.forEach(func(v uint16) {
+		if !o.contains(v) {
+			res.add(v)
+		}
+	})
+	return res
+}
+
+func (c *container) intersects(o *container) bool {
+	if c.kind == containerBitmap && o.kind == containerBitmap {
+		// bitmap containers aren't always the same length - entity masks
+		// are sized to their world's componentsCount (see
+		// NewRoaringBitmapForComponents), while a container produced by
+		// toBitmap/optimize is always the full roaringBitmapWords long.
+		// Words past the shorter container's end are implicitly zero, so
+		// they can only ever intersect if the other side is, too.
+		n := len(c.bitmap)
+		if len(o.bitmap) < n {
+			n = len(o.bitmap)
+		}
+		for i := 0; i < n; i++ {
+			if c.bitmap[i]&o.bitmap[i] != 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if c.kind == containerArray && o.kind == containerArray {
+		i, j := 0, 0
+		for i < len(c.array) && j < len(o.array) {
+			switch {
+			case c.array[i] < o.array[j]:
+				i++
+			case c.array[i] > o.array[j]:
+				j++
+			default:
+				return true
+			}
+		}
+		return false
+	}
+	found := false
+	c.forEachUntil(func(v uint16) bool {
+		if o.contains(v) {
+			found = true
+			return true
+		}
+		return false
+	})
+	return found
+}
+
+// containsAll returns true if every value of o is also present in c, i.e.
+// c is a superset of o. Like intersects, it takes a direct word-for-word
+// or merge-join fast path when both containers share an encoding, instead
+// of re-running contains (a binary search or closure call) per element.
+func (c *container) containsAll(o *container) bool {
+	if c.kind == containerBitmap && o.kind == containerBitmap {
+		// see intersects for why bitmap containers can differ in length.
+		n := len(o.bitmap)
+		if len(c.bitmap) < n {
+			n = len(c.bitmap)
+			for i := n; i < len(o.bitmap); i++ {
+				if o.bitmap[i] != 0 {
+					return false
+				}
+			}
+		}
+		for i := 0; i < n; i++ {
+			if c.bitmap[i]&o.bitmap[i] != o.bitmap[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if c.kind == containerArray && o.kind == containerArray {
+		i := 0
+		for _, v := range o.array {
+			for i < len(c.array) && c.array[i] < v {
+				i++
+			}
+			if i >= len(c.array) || c.array[i] != v {
+				return false
+			}
+		}
+		return true
+	}
+	allFound := true
+	o.forEachUntil(func(v uint16) bool {
+		if !c.contains(v) {
+			allFound = false
+			return true
+		}
+		return false
+	})
+	return allFound
+}
+
+func (c *container) forEach(f func(uint16)) {
+	c.forEachUntil(func(v uint16) bool { f(v); return false })
+}
+
+func (c *container) forEachUntil(f func(uint16) bool) {
+	switch c.kind {
+	case containerArray:
+		for _, v := range c.array {
+			if f(v) {
+				return
+			}
+		}
+	case containerBitmap:
+		for i, w := range c.bitmap {
+			for w != 0 {
+				bit := trailingZeros64(w)
+				if f(uint16(i*64 + bit)) {
+					return
+				}
+				w &= w - 1
+			}
+		}
+	default:
+		for _, r := range c.runs {
+			for v := uint32(r.start); v <= uint32(r.start)+uint32(r.length); v++ {
+				if f(uint16(v)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func popcount(w uint64) int {
+	n := 0
+	for w != 0 {
+		w &= w - 1
+		n++
+	}
+	return n
+}
+
+func trailingZeros64(w uint64) int {
+	n := 0
+	for w&1 == 0 {
+		w >>= 1
+		n++
+	}
+	return n
+}
+
+// RoaringBitmap is a compressed set of uint32 values, splitting the key
+// space into 16-bit high-part containers (array/bitmap/run encoded per
+// container), the same idea used by Roaring bitmaps in bleve/containerd.
+// Component IDs in this repo are uint16, so a RoaringBitmap built from
+// them only ever populates a single container - but the per-high-key
+// container design generalizes to any uint32 set, including fast set
+// algebra across filter result sets ("entities in A but not B").
+type RoaringBitmap struct {
+	keys       []uint16
+	containers []*container
+}
+
+// NewRoaringBitmap returns new empty instance of RoaringBitmap.
+func NewRoaringBitmap() *RoaringBitmap {
+	return &RoaringBitmap{}
+}
+
+// NewRoaringBitmapForComponents returns a RoaringBitmap with a single
+// bitmap-encoded container pre-sized for component IDs in [0, componentsCount),
+// the same sizing NewBitSet uses. Add/Remove/Contains against it are then a
+// direct word op (like BitSet.Set/Unset), not the sort.Search-plus-array-shift
+// an empty/array-encoded RoaringBitmap would pay on every call. Intended for
+// EntityData.RoaringMask, which every generated SetXxx/DelXxx mutates on
+// every call, unlike Filter's includeBitmap/excludeBitmap (built once from
+// RoaringBitmapFromIDs, read far more often than they'd ever be mutated).
+// The pre-sized container lasts only until the bitmap is emptied: Remove
+// prunes it, and later Adds go to a new array-encoded container.
+func NewRoaringBitmapForComponents(componentsCount uint16) *RoaringBitmap {
+	if componentsCount == 0 {
+		return NewRoaringBitmap()
+	}
+	words := (int(componentsCount)-1)/64 + 1
+	return &RoaringBitmap{
+		keys:       []uint16{0},
+		containers: []*container{{kind: containerBitmap, bitmap: make([]uint64, words)}},
+	}
+}
+
+func (b *RoaringBitmap) find(hi uint16) (int, bool) {
+	idx := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] >= hi })
+	return idx, idx < len(b.keys) && b.keys[idx] == hi
+}
+
+func (b *RoaringBitmap) containerFor(hi uint16, create bool) *container {
+	idx, ok := b.find(hi)
+	if ok {
+		return b.containers[idx]
+	}
+	if !create {
+		return nil
+	}
+	c := newArrayContainer()
+	b.keys = append(b.keys, 0)
+	copy(b.keys[idx+1:], b.keys[idx:])
+	b.keys[idx] = hi
+
+	b.containers = append(b.containers, nil)
+	copy(b.containers[idx+1:], b.containers[idx:])
+	b.containers[idx] = c
+	return c
+}
+
+// Add inserts v into the bitmap.
+func (b *RoaringBitmap) Add(v uint32) {
+	b.containerFor(uint16(v>>16), true).add(uint16(v))
+}
+
+// Remove deletes v from the bitmap, does nothing if its not present. A
+// container that becomes empty is pruned from keys/containers immediately,
+// so ContainsAll/Intersects/AndCardinality never have to special-case a
+// present-but-empty container left behind by a prior Remove.
+func (b *RoaringBitmap) Remove(v uint32) {
+	hi := uint16(v >> 16)
+	idx, ok := b.find(hi)
+	if !ok {
+		return
+	}
+	b.containers[idx].remove(uint16(v))
+	if b.containers[idx].cardinality() == 0 {
+		b.keys = append(b.keys[:idx], b.keys[idx+1:]...)
+		b.containers = append(b.containers[:idx], b.containers[idx+1:]...)
+	}
+}
+
+// Contains returns true if v is present in the bitmap.
+func (b *RoaringBitmap) Contains(v uint32) bool {
+	c := b.containerFor(uint16(v>>16), false)
+	return c != nil && c.contains(uint16(v))
+}
+
+// Cardinality returns count of values stored in the bitmap.
+func (b *RoaringBitmap) Cardinality() int {
+	n := 0
+	for _, c := range b.containers {
+		n += c.cardinality()
+	}
+	return n
+}
+
+// AndCardinality returns count of values present in both bitmaps,
+// without allocating the intersection itself.
+func (b *RoaringBitmap) AndCardinality(other *RoaringBitmap) int {
+	n := 0
+	for i, hi := range b.keys {
+		if oc, ok := other.find(hi); ok {
+			b.containers[i].forEach(func(v uint16) {
+				if other.containers[oc].contains(v) {
+					n++
+				}
+			})
+		}
+	}
+	return n
+}
+
+// ContainsAll returns true if every value of other is also present here,
+// i.e. receiver is a superset of other.
+func (b *RoaringBitmap) ContainsAll(other *RoaringBitmap) bool {
+	for i, hi := range other.keys {
+		idx, ok := b.find(hi)
+		if !ok {
+			return false
+		}
+		if !b.containers[idx].containsAll(other.containers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersects returns true if any value is present in both bitmaps.
+func (b *RoaringBitmap) Intersects(other *RoaringBitmap) bool {
+	for i, hi := range b.keys {
+		if oc, ok := other.find(hi); ok {
+			if b.containers[i].intersects(other.containers[oc]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IntersectsExcept is Intersects as if the single value except were absent
+// from other, letting callers test membership "as if one bit was already
+// toggled" without allocating a temporary bitmap for it.
+func (b *RoaringBitmap) IntersectsExcept(other *RoaringBitmap, except uint32) bool {
+	exceptHi, exceptLo := uint16(except>>16), uint16(except)
+	for i, hi := range other.keys {
+		bc, ok := b.find(hi)
+		if !ok {
+			continue
+		}
+		found := false
+		other.containers[i].forEachUntil(func(lo uint16) bool {
+			if hi == exceptHi && lo == exceptLo {
+				return false
+			}
+			if b.containers[bc].contains(lo) {
+				found = true
+				return true
+			}
+			return false
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// Or returns new bitmap holding the union of both bitmaps.
+func (b *RoaringBitmap) Or(other *RoaringBitmap) *RoaringBitmap {
+	res := NewRoaringBitmap()
+	b.forEach(func(v uint32) { res.Add(v) })
+	other.forEach(func(v uint32) { res.Add(v) })
+	return res
+}
+
+// AndNot returns new bitmap holding values present here but not in other.
+func (b *RoaringBitmap) AndNot(other *RoaringBitmap) *RoaringBitmap {
+	res := NewRoaringBitmap()
+	b.forEach(func(v uint32) {
+		if !other.Contains(v) {
+			res.Add(v)
+		}
+	})
+	return res
+}
+
+// Optimize converts every container to whichever of array/bitmap/run
+// encoding is smallest for its current contents: array<->bitmap at the
+// >4096-element break-even point, or run-length when it compresses
+// better than that.
+func (b *RoaringBitmap) Optimize() {
+	for _, c := range b.containers {
+		c.optimize()
+	}
+}
+
+func (b *RoaringBitmap) forEach(f func(uint32)) {
+	for i, hi := range b.keys {
+		b.containers[i].forEach(func(lo uint16) {
+			f(uint32(hi)<<16 | uint32(lo))
+		})
+	}
+}
+
+// RoaringBitmapFromIDs returns new RoaringBitmap containing every id.
+func RoaringBitmapFromIDs(ids []uint16) *RoaringBitmap {
+	b := NewRoaringBitmap()
+	for _, id := range ids {
+		b.Add(uint32(id))
+	}
+	return b
+}
@@ -0,0 +1,323 @@
+// ----------------------------------------------------------------------------
+// The MIT License
+// LecsGO - Entity Component System framework powered by Golang.
+// Url: https://github.com/Leopotam/go-ecs
+// Copyright (c) 2021 Leopotam/go-ecs
+// ----------------------------------------------------------------------------
+
+package ecs
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestRoaringBitmapAddContainsRemove(t *testing.T) {
+	b := NewRoaringBitmap()
+	ids := []uint32{0, 1, 5, 65, 4095, 4096, 4097, 70000, 70001}
+	for _, id := range ids {
+		b.Add(id)
+	}
+	for _, id := range ids {
+		if !b.Contains(id) {
+			t.Fatalf("expected %d to be contained", id)
+		}
+	}
+	if b.Contains(123456) {
+		t.Fatalf("unexpected containment")
+	}
+	if b.Cardinality() != len(ids) {
+		t.Fatalf("cardinality mismatch: %d vs %d", b.Cardinality(), len(ids))
+	}
+	b.Remove(5)
+	if b.Contains(5) {
+		t.Fatalf("5 should be removed")
+	}
+	if b.Cardinality() != len(ids)-1 {
+		t.Fatalf("cardinality mismatch after remove")
+	}
+}
+
+func TestRoaringBitmapSetOps(t *testing.T) {
+	a := RoaringBitmapFromIDs([]uint16{1, 2, 3, 65})
+	b := RoaringBitmapFromIDs([]uint16{2, 3, 70})
+
+	if a.AndCardinality(b) != 2 {
+		t.Fatalf("and-cardinality mismatch: %d", a.AndCardinality(b))
+	}
+	if !a.Intersects(b) {
+		t.Fatalf("expected intersection")
+	}
+	if RoaringBitmapFromIDs([]uint16{1}).Intersects(RoaringBitmapFromIDs([]uint16{2})) {
+		t.Fatalf("unexpected intersection")
+	}
+	union := a.Or(b)
+	for _, v := range []uint32{1, 2, 3, 65, 70} {
+		if !union.Contains(v) {
+			t.Fatalf("union missing %d", v)
+		}
+	}
+	diff := a.AndNot(b)
+	if diff.Contains(2) || diff.Contains(3) {
+		t.Fatalf("andnot should drop shared bits")
+	}
+	if !diff.Contains(1) || !diff.Contains(65) {
+		t.Fatalf("andnot dropped bits unique to a")
+	}
+	if !a.ContainsAll(RoaringBitmapFromIDs([]uint16{2, 3})) {
+		t.Fatalf("a should contain all of {2,3}")
+	}
+	if a.ContainsAll(b) {
+		t.Fatalf("a should not contain all of b (70 is missing)")
+	}
+}
+
+func TestRoaringBitmapIntersectsExcept(t *testing.T) {
+	entity := RoaringBitmapFromIDs([]uint16{1, 2, 3})
+	exclude := RoaringBitmapFromIDs([]uint16{3})
+	if entity.IntersectsExcept(exclude, 3) {
+		t.Fatalf("expected no intersection once 3 is excepted")
+	}
+	if !entity.IntersectsExcept(exclude, 99) {
+		t.Fatalf("excepting an unrelated id should not hide the real intersection")
+	}
+}
+
+// TestRoaringBitmapRemovePrunesEmptyContainer guards against a leftover
+// empty container making ContainsAll/Intersects wrong: a bitmap that once
+// held a value at some high key but had it Remove()d should behave exactly
+// like one that never touched that high key at all.
+func TestRoaringBitmapRemovePrunesEmptyContainer(t *testing.T) {
+	other := NewRoaringBitmap()
+	other.Add(70000) // high key 1
+	other.Remove(70000)
+	if other.Cardinality() != 0 {
+		t.Fatalf("expected empty bitmap after removing its only value")
+	}
+
+	receiver := NewRoaringBitmap()
+	receiver.Add(1) // only ever touches high key 0
+
+	if !receiver.ContainsAll(other) {
+		t.Fatalf("ContainsAll should be vacuously true against an emptied-out bitmap")
+	}
+	if receiver.Intersects(other) {
+		t.Fatalf("an emptied-out bitmap should never intersect anything")
+	}
+}
+
+func TestRoaringBitmapOptimizePreservesContents(t *testing.T) {
+	dense := NewRoaringBitmap()
+	for i := uint32(0); i < 5000; i++ {
+		dense.Add(i)
+	}
+	dense.Optimize()
+	if dense.Cardinality() != 5000 {
+		t.Fatalf("cardinality changed after optimize: %d", dense.Cardinality())
+	}
+	for i := uint32(0); i < 5000; i++ {
+		if !dense.Contains(i) {
+			t.Fatalf("lost %d after optimize", i)
+		}
+	}
+
+	sparse := RoaringBitmapFromIDs([]uint16{1, 2, 3, 1000})
+	sparse.Optimize()
+	for _, v := range []uint32{1, 2, 3, 1000} {
+		if !sparse.Contains(v) {
+			t.Fatalf("sparse lost %d after optimize", v)
+		}
+	}
+}
+
+// TestRoaringBitmapStressVsModel cross-checks Add/Remove/Optimize against a
+// plain map model across a large randomized sequence.
+func TestRoaringBitmapStressVsModel(t *testing.T) {
+	model := map[uint32]bool{}
+	rb := NewRoaringBitmap()
+	rng := rand.New(rand.NewSource(42))
+	for i := 0; i < 20000; i++ {
+		v := uint32(rng.Intn(200000))
+		if rng.Intn(2) == 0 {
+			model[v] = true
+			rb.Add(v)
+		} else {
+			delete(model, v)
+			rb.Remove(v)
+		}
+		if i%500 == 499 {
+			rb.Optimize()
+		}
+	}
+	if rb.Cardinality() != len(model) {
+		t.Fatalf("stress cardinality mismatch: %d vs %d", rb.Cardinality(), len(model))
+	}
+	for v := range model {
+		if !rb.Contains(v) {
+			t.Fatalf("stress: missing %d", v)
+		}
+	}
+}
+
+func TestFilterIsCompatibleBitmap(t *testing.T) {
+	f := NewFilter([]uint16{1, 2}, []uint16{3}, 8)
+	entityMask := RoaringBitmapFromIDs([]uint16{1, 2})
+	if !f.IsCompatibleBitmap(entityMask) {
+		t.Fatalf("expected compatible")
+	}
+	entityMask.Add(3)
+	if f.IsCompatibleBitmap(entityMask) {
+		t.Fatalf("expected incompatible due to exclude")
+	}
+}
+
+func TestFilterIsCompatibleBitmapWithout(t *testing.T) {
+	f := NewFilter([]uint16{1, 2}, []uint16{3}, 8)
+
+	// as-if component 1 (required) is about to be removed: incompatible.
+	entityMask := RoaringBitmapFromIDs([]uint16{1, 2})
+	if f.IsCompatibleBitmapWithout(entityMask, 1) {
+		t.Fatalf("should be incompatible once a required component is toggled out")
+	}
+
+	// as-if the excluded component 3 (still present) is about to be removed: compatible.
+	entityMask = RoaringBitmapFromIDs([]uint16{1, 2, 3})
+	if !f.IsCompatibleBitmapWithout(entityMask, 3) {
+		t.Fatalf("should be compatible once the excluded component is toggled out")
+	}
+
+	// an unrelated component toggling out changes nothing.
+	entityMask = RoaringBitmapFromIDs([]uint16{1, 2, 3})
+	if f.IsCompatibleBitmapWithout(entityMask, 9) {
+		t.Fatalf("toggling an unrelated id should not change the verdict")
+	}
+}
+
+// TestUpdateFiltersRoaringMatchesSortedMask drives World.UpdateFilters (which
+// now uses entityData.RoaringMask/IsCompatibleBitmap) through a Set/Del
+// sequence and checks filter membership after each step against hand-written
+// expectations, the same verdicts the sort.Search path gives for that Mask -
+// a correctness regression test for the switch away from the sort.Search path.
+func TestUpdateFiltersRoaringMatchesSortedMask(t *testing.T) {
+	pools := []ComponentPool{&noopPool{}, &noopPool{}, &noopPool{}}
+	filters := []Filter{
+		*NewFilter([]uint16{0, 1}, []uint16{2}, 8),
+	}
+	w := NewWorld(8, pools, filters)
+	e := w.NewEntity()
+	entityData := &w.Entities[e]
+
+	set := func(id uint16) {
+		entityData.BitMask.Set(id)
+		maskIdx := sort.Search(len(entityData.Mask), func(i int) bool { return entityData.Mask[i] > id })
+		entityData.Mask = append(entityData.Mask, 0)
+		copy(entityData.Mask[maskIdx+1:], entityData.Mask[maskIdx:])
+		entityData.Mask[maskIdx] = id
+		entityData.RoaringMask.Add(uint32(id))
+		w.UpdateFilters(e, id, true)
+	}
+	del := func(id uint16) {
+		w.UpdateFilters(e, id, false)
+		maskLen := len(entityData.Mask)
+		maskIdx := sort.Search(maskLen, func(i int) bool { return entityData.Mask[i] >= id })
+		copy(entityData.Mask[maskIdx:], entityData.Mask[maskIdx+1:])
+		entityData.Mask = entityData.Mask[:maskLen-1]
+		entityData.BitMask.Unset(id)
+		entityData.RoaringMask.Remove(uint32(id))
+	}
+
+	inFilter := func() bool {
+		_, ok := w.filters[0].entitiesMap[e]
+		return ok
+	}
+
+	set(0)
+	if inFilter() {
+		t.Fatalf("should not match with only component 0")
+	}
+	set(1)
+	if !inFilter() {
+		t.Fatalf("should match with components 0 and 1")
+	}
+	set(2)
+	if inFilter() {
+		t.Fatalf("should not match once excluded component 2 is added")
+	}
+	del(2)
+	if !inFilter() {
+		t.Fatalf("should match again once excluded component 2 is removed")
+	}
+	del(0)
+	if inFilter() {
+		t.Fatalf("should not match once a required component is removed")
+	}
+}
+
+type noopPool struct{}
+
+func (p *noopPool) New()           {}
+func (p *noopPool) Recycle(uint32) {}
+
+// component counts benchmarked below, matching what the original request asked for.
+var benchComponentCounts = []int{8, 32, 128, 512}
+
+func buildBenchFilterAndMask(n int) (*Filter, *EntityData, *RoaringBitmap) {
+	include := make([]uint16, 0, n/2)
+	for i := 0; i < n; i += 2 {
+		include = append(include, uint16(i))
+	}
+	f := NewFilter(include, []uint16{uint16(n - 1)}, 8)
+
+	entityData := &EntityData{Mask: make([]uint16, 0, n)}
+	for i := 0; i < n-1; i++ {
+		entityData.Mask = append(entityData.Mask, uint16(i))
+		entityData.RoaringMask.Add(uint32(i))
+	}
+	sort.Slice(entityData.Mask, func(i, j int) bool { return entityData.Mask[i] < entityData.Mask[j] })
+	return f, entityData, &entityData.RoaringMask
+}
+
+// BenchmarkIsCompatible benchmarks the original sort.Search-over-Mask path,
+// one sub-benchmark per component count.
+func BenchmarkIsCompatible(b *testing.B) {
+	for _, n := range benchComponentCounts {
+		f, entityData, _ := buildBenchFilterAndMask(n)
+		b.Run(itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f.isCompatible(entityData)
+			}
+		})
+	}
+}
+
+// BenchmarkIsCompatibleBitmap benchmarks the RoaringBitmap-based path that
+// World.UpdateFilters now uses in place of isCompatible, one sub-benchmark
+// per component count.
+func BenchmarkIsCompatibleBitmap(b *testing.B) {
+	for _, n := range benchComponentCounts {
+		f, _, mask := buildBenchFilterAndMask(n)
+		b.Run(itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f.IsCompatibleBitmap(mask)
+			}
+		})
+	}
+}
+
+func itoa(n int) string {
+	digits := "0123456789"
+	if n == 0 {
+		return "0"
+	}
+	var buf [8]byte
+	pos := len(buf)
+	for n > 0 {
+		pos--
+		buf[pos] = digits[n%10]
+		n /= 10
+	}
+	return string(buf[pos:])
+}
@@ -175,6 +175,9 @@ func (s *Systems) Init() {
 
 // Run processes RunSystem systems execution.
 func (s *Systems) Run() {
+	for _, w := range s.worlds {
+		w.InternalWorld().swapEvents()
+	}
 	for _, system := range s.runSystems {
 		system.Run(s)
 		if DEBUG {
@@ -185,6 +188,9 @@ func (s *Systems) Run() {
 				if w.InternalWorld().checkLeakedFilters() {
 					panic(fmt.Sprintf("filter invalid lock/unlock detected after %T.Run()", system))
 				}
+				if w.InternalWorld().checkLeakedEvents() {
+					panic(fmt.Sprintf("event reader missed events after %T.Run()", system))
+				}
 			}
 		}
 	}
@@ -19,14 +19,16 @@ type CustomWorld interface {
 
 // EntityData - container for keeping internal entity data.
 type EntityData struct {
-	Gen     int16
-	BitMask BitSet
-	Mask    []uint16
+	Gen         int16
+	BitMask     BitSet
+	Mask        []uint16
+	RoaringMask RoaringBitmap
 }
 
 // World - container for all data.
 type World struct {
 	Pools            []ComponentPool
+	Events           []eventsContainer
 	filters          []Filter
 	filtersByInclude [][]*Filter
 	filtersByExclude [][]*Filter
@@ -77,6 +79,13 @@ func NewWorld(entitiesCount uint32, pools []ComponentPool, filters []Filter) *Wo
 	return &w
 }
 
+// AddEvents registers event containers for this world, order must match
+// the indices used by the generated SendXxx/NewXxxReader helpers.
+func (w *World) AddEvents(events ...eventsContainer) *World {
+	w.Events = events
+	return w
+}
+
 // Destroy processes cleanup of data inside world.
 func (w *World) Destroy() {
 	for i := 0; i < len(w.Entities); i++ {
@@ -102,9 +111,10 @@ func (w *World) NewEntity() Entity {
 		// create new entity.
 		entity = Entity(len(w.Entities))
 		entityData := EntityData{
-			Gen:     1,
-			BitMask: NewBitSet(w.componentsCount),
-			Mask:    make([]uint16, 0, w.componentsCount),
+			Gen:         1,
+			BitMask:     NewBitSet(w.componentsCount),
+			Mask:        make([]uint16, 0, w.componentsCount),
+			RoaringMask: *NewRoaringBitmapForComponents(w.componentsCount),
 		}
 		w.Entities = append(w.Entities, entityData)
 		for _, p := range w.Pools {
@@ -128,6 +138,7 @@ func (w *World) DelEntity(entity Entity) {
 		w.Pools[typeID].Recycle(entity)
 		entityData.Mask = entityData.Mask[:i]
 		entityData.BitMask.Unset(typeID)
+		entityData.RoaringMask.Remove(uint32(typeID))
 	}
 	// entityData.Mask = entityData.Mask[:0]
 	gen++
@@ -155,6 +166,9 @@ func (w *World) UnpackEntity(packedEntity PackedEntity) (Entity, bool) {
 }
 
 // UpdateFilters updates all compatible with requested component filters.
+// Compatibility is tested through each Filter's RoaringBitmap (see
+// Filter.IsCompatibleBitmap/IsCompatibleBitmapWithout), kept in sync with
+// entityData.Mask/BitMask by every Set/Del call site.
 func (w *World) UpdateFilters(e Entity, componentType uint16, add bool) {
 	entityData := &w.Entities[e]
 	includeList := w.filtersByInclude[componentType]
@@ -162,7 +176,7 @@ func (w *World) UpdateFilters(e Entity, componentType uint16, add bool) {
 	if add {
 		// add component.
 		for _, f := range includeList {
-			if f.isCompatible(entityData) {
+			if f.IsCompatibleBitmap(&entityData.RoaringMask) {
 				if DEBUG {
 					if _, ok := f.entitiesMap[e]; ok {
 						panic("entity already in filter")
@@ -172,7 +186,7 @@ func (w *World) UpdateFilters(e Entity, componentType uint16, add bool) {
 			}
 		}
 		for _, f := range excludeList {
-			if f.isCompatibleWithout(entityData, componentType) {
+			if f.IsCompatibleBitmapWithout(&entityData.RoaringMask, componentType) {
 				if DEBUG {
 					if _, ok := f.entitiesMap[e]; !ok {
 						panic("entity not in filter")
@@ -184,7 +198,7 @@ func (w *World) UpdateFilters(e Entity, componentType uint16, add bool) {
 	} else {
 		// remove component.
 		for _, f := range includeList {
-			if f.isCompatible(entityData) {
+			if f.IsCompatibleBitmap(&entityData.RoaringMask) {
 				if DEBUG {
 					if _, ok := f.entitiesMap[e]; !ok {
 						panic("entity not in filter")
@@ -194,7 +208,7 @@ func (w *World) UpdateFilters(e Entity, componentType uint16, add bool) {
 			}
 		}
 		for _, f := range excludeList {
-			if f.isCompatibleWithout(entityData, componentType) {
+			if f.IsCompatibleBitmapWithout(&entityData.RoaringMask, componentType) {
 				if DEBUG {
 					if _, ok := f.entitiesMap[e]; ok {
 						panic("entity already in filter")
@@ -206,6 +220,20 @@ func (w *World) UpdateFilters(e Entity, componentType uint16, add bool) {
 	}
 }
 
+// AddToFilter appends entity directly to filter at idx, bypassing
+// compatibility checks. Used by generated archetype-graph fast-path code
+// that already knows the entity is compatible with the filter.
+func (w *World) AddToFilter(idx int, e Entity) {
+	w.filters[idx].add(e)
+}
+
+// RemoveFromFilter removes entity directly from filter at idx, bypassing
+// compatibility checks. Used by generated archetype-graph fast-path code
+// that already knows the entity left the filter.
+func (w *World) RemoveFromFilter(idx int, e Entity) {
+	w.filters[idx].remove(e)
+}
+
 func (w *World) checkLeakedEntities() bool {
 	if len(w.leakedEntities) > 0 {
 		for _, e := range w.leakedEntities {
@@ -226,3 +254,21 @@ func (w *World) checkLeakedFilters() bool {
 	}
 	return false
 }
+
+// swapEvents swaps the double-buffers of all registered event containers,
+// dropping events that were not consumed by any reader within the
+// previous Systems.Run() cycle.
+func (w *World) swapEvents() {
+	for _, e := range w.Events {
+		e.swap()
+	}
+}
+
+func (w *World) checkLeakedEvents() bool {
+	for _, e := range w.Events {
+		if e.checkLeaked() {
+			return true
+		}
+	}
+	return false
+}